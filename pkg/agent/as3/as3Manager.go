@@ -17,10 +17,15 @@
 package as3
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/F5Networks/k8s-bigip-ctlr/pkg/writer"
@@ -29,6 +34,14 @@ import (
 	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
 )
 
+// Quorum policies controlling when a fan-out post across multiple
+// PostManagers is considered successful.
+const (
+	quorumAll      = "all"
+	quorumMajority = "majority"
+	quorumAny      = "any"
+)
+
 const (
 	svcTenantLabel      = "cis.f5.com/as3-tenant="
 	svcAppLabel         = "cis.f5.com/as3-app="
@@ -46,6 +59,10 @@ const (
 	as3template          = "template"
 	//as3SchemaLatestURL   = "https://raw.githubusercontent.com/F5Networks/f5-appsvcs-extension/master/schema/latest/as3-schema.json"
 	as3SchemaFileName = "as3-schema-3.21.0-4-cis.json"
+	// defaultAS3SchemaBaseURL is the upstream f5-appsvcs-extension schema
+	// tree CIS fetches versioned schemas from when Params.SchemaBaseURL is
+	// left unset.
+	defaultAS3SchemaBaseURL = "https://raw.githubusercontent.com/F5Networks/f5-appsvcs-extension/master/schema"
 )
 
 var baseAS3Config = `{
@@ -73,6 +90,14 @@ type AS3Config struct {
 	unifiedDeclaration    as3Declaration
 }
 
+// DryRunResult is sent on RspChan in place of the usual MessageResponse
+// when DryRun is enabled, carrying the diff BIG-IP returned for a
+// declaration CIS never actually applied.
+type DryRunResult struct {
+	IsResponseSuccessful bool
+	Diff                 string
+}
+
 // ActiveAS3ConfigMap user defined ConfigMap for global availability.
 type AS3ConfigMap struct {
 	Name      string   // AS3 specific ConfigMap name
@@ -95,11 +120,52 @@ type AS3Manager struct {
 	OverriderCfgMapName string
 	// Path of schemas reside locally
 	SchemaLocalPath string
-	// POSTs configuration to BIG-IP using AS3
-	PostManager *PostManager
+	// SchemaBaseURL is fetched from to resolve the AS3 schema matching the
+	// BIG-IP's AS3 version/build, falling back to SchemaLocalPath on
+	// network failure. Defaults to defaultAS3SchemaBaseURL.
+	SchemaBaseURL string
+	// SchemaChecksums pins the SHA256 of a fetched schema by "<version>-
+	// <build>" release string, so air-gapped operators can trust a cached
+	// artifact.
+	SchemaChecksums map[string]string
+	// POSTs configuration to every target BIG-IP using AS3. A single-device
+	// deployment still gets a one-element slice.
+	PostManagers []*PostManager
+	// quorum controls how many PostManagers must succeed for a fan-out post
+	// to be reported as successful on RspChan: quorumAll (default),
+	// quorumMajority, or quorumAny.
+	quorum string
+	// failedTargets is the subset of PostManagers that failed the last post,
+	// even if quorum was otherwise met, so ConfigDeployer keeps reconciling
+	// via postOnEventOrTimeout until every target is caught up instead of
+	// leaving a quorum-masked target out of sync until the next declaration.
+	failedTargets []*PostManager
 	// To put list of tenants in BIG-IP REST call URL that are in AS3 declaration
 	FilterTenants    bool
 	DefaultPartition string
+	// When true, CIS POSTs only the Applications it owns inside a shared
+	// Tenant instead of replacing the whole Tenant declaration.
+	PerAppMode bool
+	// Tenants that PerAppMode applies to. When empty, PerAppMode applies to
+	// every tenant in the declaration.
+	PerAppTenants []string
+	// lastDeletedPerApps is the tenant/application pairs deleted on the most
+	// recent PerAppMode post, so postOnEventOrTimeout can retry those
+	// deletions against failedTargets too.
+	lastDeletedPerApps map[string][]string
+	// Explicit allowlist of tenants CIS is permitted to manage on a shared
+	// BIG-IP. When empty, CIS manages every tenant it finds in its own
+	// declaration, as before. When set, CIS never posts to or deletes a
+	// tenant outside this list, even if that tenant disappears from the
+	// declaration.
+	TenantFilter []string
+	// DryRun previews the effect of a declaration on BIG-IP instead of
+	// applying it: declarations are still built and validated, but posted
+	// with action=dry-run and never written to as3ActiveConfig.
+	DryRun bool
+	// lastDryRunResult is the most recent dry-run diff, served by
+	// DryRunHandler.
+	lastDryRunResult DryRunResult
 	ReqChan          chan MessageRequest
 	RspChan          chan interface{}
 	userAgent        string
@@ -123,14 +189,35 @@ type Params struct {
 	//Agent                     string
 	OverriderCfgMapName string
 	SchemaLocalPath     string
+	SchemaBaseURL       string
+	SchemaChecksums     map[string]string
 	FilterTenants       bool
-	BIGIPUsername       string
-	BIGIPPassword       string
-	BIGIPURL            string
-	TrustedCerts        string
-	AS3PostDelay        int
-	ConfigWriter        writer.Writer
-	EventChan           chan interface{}
+	PerAppMode          bool
+	PerAppTenants       []string
+	TenantFilter        []string
+	// DryRun previews declarations against BIG-IP without applying them,
+	// see AS3Manager.DryRun.
+	DryRun        bool
+	BIGIPUsername string
+	BIGIPPassword string
+	BIGIPURL      string
+	TrustedCerts  string
+	// BIGIPTargets fans identical AS3 declarations out to a device-group or
+	// active/standby pair. When empty, the single BIGIPUsername/BIGIPPassword
+	// /BIGIPURL/TrustedCerts quad above is used, as before.
+	BIGIPTargets []BIGIPTarget
+	// Quorum is "all" (default), "majority", or "any" - how many
+	// BIGIPTargets must accept a post for it to be considered successful.
+	Quorum       string
+	AS3PostDelay int
+	ConfigWriter writer.Writer
+	EventChan    chan interface{}
+	// AuthMode selects how PostManager authenticates to BIG-IP: "basic"
+	// (default) or "token" for F5 iControl token auth.
+	AuthMode string
+	// ExternalAuthProviderName authenticates against a remote auth provider
+	// (LDAP/RADIUS/TACACS) when AuthMode is "token".
+	ExternalAuthProviderName string
 	//Log the AS3 response body in Controller logs
 	LogResponse               bool
 	RspChan                   chan interface{}
@@ -149,7 +236,13 @@ func NewAS3Manager(params *Params) *AS3Manager {
 		tls13CipherGroupReference: params.TLS13CipherGroupReference,
 		ciphers:                   params.Ciphers,
 		SchemaLocalPath:           params.SchemaLocal,
+		SchemaBaseURL:             params.SchemaBaseURL,
+		SchemaChecksums:           params.SchemaChecksums,
 		FilterTenants:             params.FilterTenants,
+		PerAppMode:                params.PerAppMode,
+		PerAppTenants:             params.PerAppTenants,
+		TenantFilter:              params.TenantFilter,
+		DryRun:                    params.DryRun,
 		RspChan:                   params.RspChan,
 		userAgent:                 params.UserAgent,
 		as3Version:                params.As3Version,
@@ -157,14 +250,8 @@ func NewAS3Manager(params *Params) *AS3Manager {
 		OverriderCfgMapName:       params.OverriderCfgMapName,
 		l2l3Agent: L2L3Agent{eventChan: params.EventChan,
 			configWriter: params.ConfigWriter},
-		PostManager: NewPostManager(PostParams{
-			BIGIPUsername: params.BIGIPUsername,
-			BIGIPPassword: params.BIGIPPassword,
-			BIGIPURL:      params.BIGIPURL,
-			TrustedCerts:  params.TrustedCerts,
-			SSLInsecure:   params.SSLInsecure,
-			AS3PostDelay:  params.AS3PostDelay,
-			LogResponse:   params.LogResponse}),
+		PostManagers: buildPostManagers(params),
+		quorum:       normalizeQuorum(params.Quorum),
 	}
 
 	as3Manager.fetchAS3Schema()
@@ -172,6 +259,126 @@ func NewAS3Manager(params *Params) *AS3Manager {
 	return &as3Manager
 }
 
+// buildPostManagers constructs one PostManager per configured BIGIPTarget,
+// falling back to a single PostManager built from the legacy single-device
+// fields when no BIGIPTargets are given.
+func buildPostManagers(params *Params) []*PostManager {
+	if len(params.BIGIPTargets) == 0 {
+		return []*PostManager{NewPostManager(PostParams{
+			BIGIPUsername:            params.BIGIPUsername,
+			BIGIPPassword:            params.BIGIPPassword,
+			BIGIPURL:                 params.BIGIPURL,
+			TrustedCerts:             params.TrustedCerts,
+			SSLInsecure:              params.SSLInsecure,
+			AS3PostDelay:             params.AS3PostDelay,
+			LogResponse:              params.LogResponse,
+			AuthMode:                 params.AuthMode,
+			ExternalAuthProviderName: params.ExternalAuthProviderName,
+		})}
+	}
+
+	postManagers := make([]*PostManager, 0, len(params.BIGIPTargets))
+	for _, target := range params.BIGIPTargets {
+		postManagers = append(postManagers, NewPostManager(PostParams{
+			BIGIPUsername:            target.BIGIPUsername,
+			BIGIPPassword:            target.BIGIPPassword,
+			BIGIPURL:                 target.BIGIPURL,
+			TrustedCerts:             target.TrustedCerts,
+			SSLInsecure:              params.SSLInsecure,
+			AS3PostDelay:             params.AS3PostDelay,
+			LogResponse:              params.LogResponse,
+			AuthMode:                 params.AuthMode,
+			ExternalAuthProviderName: params.ExternalAuthProviderName,
+			DefaultPartition:         target.DefaultPartition,
+		}))
+	}
+	return postManagers
+}
+
+// normalizeQuorum maps an operator-supplied quorum string to a known
+// policy, defaulting to quorumAll.
+func normalizeQuorum(q string) string {
+	switch q {
+	case quorumMajority, quorumAny:
+		return q
+	default:
+		return quorumAll
+	}
+}
+
+// postToTargets runs fn concurrently against every PostManager in targets,
+// aggregates the outcome according to am.quorum, and returns the subset of
+// targets that did not succeed so the caller can retry just those.
+func (am *AS3Manager) postToTargets(targets []*PostManager, fn func(pm *PostManager) (bool, string)) (bool, string, []*PostManager) {
+	type result struct {
+		pm     *PostManager
+		posted bool
+		event  string
+	}
+	results := make([]result, len(targets))
+	var wg sync.WaitGroup
+	for i, pm := range targets {
+		wg.Add(1)
+		go func(i int, pm *PostManager) {
+			defer wg.Done()
+			posted, event := fn(pm)
+			results[i] = result{pm: pm, posted: posted, event: event}
+		}(i, pm)
+	}
+	wg.Wait()
+
+	var failed []*PostManager
+	var lastEvent string
+	successCount := 0
+	for _, r := range results {
+		if r.posted {
+			successCount++
+		} else {
+			failed = append(failed, r.pm)
+			lastEvent = r.event
+		}
+	}
+
+	if ok := am.meetsQuorum(successCount, len(results)); ok {
+		return true, responseStatusOk, failed
+	}
+	return false, lastEvent, failed
+}
+
+// dedupeTargets collapses repeated PostManager entries, preserving first
+// occurrence order, so a target that failed more than one post in the same
+// round (e.g. several per-app POSTs) is only retried once.
+func dedupeTargets(targets []*PostManager) []*PostManager {
+	if len(targets) < 2 {
+		return targets
+	}
+	seen := make(map[*PostManager]bool, len(targets))
+	deduped := make([]*PostManager, 0, len(targets))
+	for _, pm := range targets {
+		if seen[pm] {
+			continue
+		}
+		seen[pm] = true
+		deduped = append(deduped, pm)
+	}
+	return deduped
+}
+
+// meetsQuorum applies am.quorum to a fan-out post's per-target results.
+func (am *AS3Manager) meetsQuorum(successCount, total int) bool {
+	if total == 0 {
+		return true
+	}
+	switch am.quorum {
+	case quorumAny:
+		return successCount > 0
+	case quorumMajority:
+		return successCount*2 > total
+	default:
+		return successCount == total
+	}
+}
+
 func (am *AS3Manager) postAS3Declaration(rsReq ResourceRequest) (bool, string) {
 
 	am.ResourceRequest = rsReq
@@ -203,17 +410,259 @@ func (am *AS3Manager) postAS3Config(tempAS3Config AS3Config) (bool, string) {
 		}
 	}
 
+	if am.DryRun {
+		return am.postDryRunAS3Config(unifiedDecl)
+	}
+
 	log.Debugf("[AS3] Posting AS3 Declaration")
 
+	if am.PerAppMode {
+		// getDeletedPerApps must run against the pre-update as3ActiveConfig,
+		// the same way getDeletedTenants diffs against it above, before
+		// updateConfig overwrites resourceConfig with tempAS3Config's.
+		// mergedPerAppTenants folds in tempAS3Config.configmaps too, so a
+		// ConfigMap-sourced Application is diffed/posted the same way a
+		// Route-derived one is.
+		deletedApps := am.getDeletedPerApps(mergedPerAppTenants(tempAS3Config))
+		am.as3ActiveConfig.updateConfig(tempAS3Config)
+		return am.postPerAppAS3Config(tempAS3Config, deletedApps)
+	}
+
 	am.as3ActiveConfig.updateConfig(tempAS3Config)
 
 	var tenants []string = nil
 
-	if am.FilterTenants {
-		tenants = getTenants(unifiedDecl, true)
+	if am.FilterTenants || len(am.TenantFilter) > 0 {
+		tenants = am.intersectTenantFilter(getTenants(unifiedDecl, true))
+	}
+	if len(am.TenantFilter) > 0 && len(tenants) == 0 {
+		// An unscoped declare URL would apply every Tenant in unifiedDecl,
+		// including ones outside TenantFilter.
+		log.Debugf("[AS3] No tenant in the declaration is within TenantFilter, skipping post")
+		am.failedTargets = nil
+		return true, ""
+	}
+
+	posted, event, failed := am.postToTargets(am.PostManagers, func(pm *PostManager) (bool, string) {
+		decl, targetTenants := unifiedDecl, tenants
+		if pm.DefaultPartition != "" {
+			decl = am.rekeyPartition(unifiedDecl, pm.DefaultPartition)
+			if len(targetTenants) > 0 {
+				// tenants was already computed against the original Tenant
+				// name and TenantFilter above; decl's Tenant is now named
+				// pm.DefaultPartition, so the declare URL must scope to
+				// that name instead, not the pre-rekey one.
+				targetTenants = []string{pm.DefaultPartition}
+			}
+		}
+		return pm.postConfig(string(decl), targetTenants)
+	})
+	am.failedTargets = failed
+	return posted, event
+}
+
+// rekeyPartition renames unifiedDecl's single Tenant to partition, so the
+// same declaration can be posted to a device that keeps CIS's objects under
+// a different BIG-IP partition (e.g. blue/green). unifiedDecl is returned
+// unchanged if it doesn't contain exactly one Tenant, since there's no
+// unambiguous single partition to rekey multiple Tenants to.
+func (am *AS3Manager) rekeyPartition(unifiedDecl as3Declaration, partition string) as3Declaration {
+	tenants := getTenants(unifiedDecl, false)
+	if len(tenants) != 1 {
+		log.Errorf("[AS3] DefaultPartition %v requires exactly one Tenant in the declaration, found %v; posting unchanged", partition, len(tenants))
+		return unifiedDecl
+	}
+
+	var as3Obj map[string]interface{}
+	if err := json.Unmarshal([]byte(unifiedDecl), &as3Obj); err != nil {
+		log.Errorf("[AS3] Unable to parse AS3 declaration for DefaultPartition %v: %v", partition, err)
+		return unifiedDecl
+	}
+	adc, ok := as3Obj["declaration"].(map[string]interface{})
+	if !ok {
+		return unifiedDecl
+	}
+	if tenants[0] == partition {
+		// Already under the right partition name; renaming would just
+		// delete it out from under itself.
+		return unifiedDecl
+	}
+	adc[partition] = adc[tenants[0]]
+	delete(adc, tenants[0])
+
+	rekeyed, err := json.Marshal(as3Obj)
+	if err != nil {
+		log.Errorf("[AS3] Marshaling AS3 declaration rekeyed to DefaultPartition %v: %v", partition, err)
+		return unifiedDecl
+	}
+	return as3Declaration(rekeyed)
+}
+
+// postDryRunAS3Config previews unifiedDecl against the primary BIG-IP
+// target without applying it, and reports the resulting diff on RspChan as
+// a DryRunResult instead of the usual MessageResponse.
+func (am *AS3Manager) postDryRunAS3Config(unifiedDecl as3Declaration) (bool, string) {
+	log.Debugf("[AS3] Posting AS3 Declaration in dry-run mode")
+
+	var tenants []string = nil
+	if am.FilterTenants || len(am.TenantFilter) > 0 {
+		tenants = am.intersectTenantFilter(getTenants(unifiedDecl, true))
+	}
+
+	posted, diff := am.PostManagers[0].postDryRunConfig(string(unifiedDecl), tenants)
+	am.lastDryRunResult = DryRunResult{IsResponseSuccessful: posted, Diff: diff}
+	am.postDryRunResponse(am.lastDryRunResult)
+	return posted, diff
+}
+
+// postDryRunResponse sends result on RspChan, mirroring
+// postAgentResponse's non-blocking replace-stale-message behavior.
+func (am *AS3Manager) postDryRunResponse(result DryRunResult) {
+	select {
+	case am.RspChan <- result:
+	case <-am.RspChan:
+		am.RspChan <- result
+	}
+}
+
+// DryRunHandler serves the most recent dry-run diff as JSON. Mount it on
+// the controller's HTTP mux, e.g.
+// http.HandleFunc("/debug/as3-dry-run", am.DryRunHandler), to let users
+// preview the effect of Route/ConfigMap changes before flipping DryRun off.
+func (am *AS3Manager) DryRunHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(am.lastDryRunResult); err != nil {
+		log.Errorf("[AS3] Encoding dry-run response: %v", err)
 	}
+}
 
-	return am.PostManager.postConfig(string(unifiedDecl), tenants)
+// postPerAppAS3Config POSTs only the Applications CIS owns, one per-app
+// sub-declaration at a time, instead of replacing the whole shared Tenant.
+// It also deletes any Application that dropped out of the declaration since
+// the last post.
+func (am *AS3Manager) postPerAppAS3Config(cfg AS3Config, deletedApps map[string][]string) (bool, string) {
+	am.lastDeletedPerApps = deletedApps
+	return am.postPerAppToTargets(cfg, deletedApps, am.PostManagers)
+}
+
+// postPerAppToTargets does the actual per-app POSTing/deleting that
+// postPerAppAS3Config and postOnEventOrTimeout's retry path share, scoped to
+// targets instead of always am.PostManagers so a retry can hit only the
+// targets that failed last time.
+func (am *AS3Manager) postPerAppToTargets(cfg AS3Config, deletedApps map[string][]string, targets []*PostManager) (bool, string) {
+	posted := true
+	event := responseStatusOk
+	var failed []*PostManager
+
+	for tenantName, tenant := range mergedPerAppTenants(cfg) {
+		if !am.ownsPerAppTenant(tenantName) || !am.tenantAllowed(tenantName) {
+			continue
+		}
+		tenantDecl, ok := tenant.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for appName, appDecl := range tenantDecl {
+			if appName == as3class {
+				continue
+			}
+			subDecl, err := json.Marshal(map[string]interface{}{
+				"schemaVersion": am.as3Version,
+				appName:         appDecl,
+			})
+			if err != nil {
+				log.Errorf("[AS3] Marshaling per-app declaration for %v/%v: %v", tenantName, appName, err)
+				continue
+			}
+			ok, ev, f := am.postToTargets(targets, func(pm *PostManager) (bool, string) {
+				tenant := tenantName
+				if pm.DefaultPartition != "" {
+					tenant = pm.DefaultPartition
+				}
+				return pm.postPerAppConfig(tenant, appName, string(subDecl))
+			})
+			if !ok {
+				posted, event = false, ev
+			}
+			failed = append(failed, f...)
+		}
+	}
+
+	for tenantName, appNames := range deletedApps {
+		for _, appName := range appNames {
+			ok, ev, f := am.postToTargets(targets, func(pm *PostManager) (bool, string) {
+				tenant := tenantName
+				if pm.DefaultPartition != "" {
+					tenant = pm.DefaultPartition
+				}
+				return pm.deletePerAppConfig(tenant, appName)
+			})
+			if !ok {
+				posted, event = false, ev
+			}
+			failed = append(failed, f...)
+		}
+	}
+
+	am.failedTargets = dedupeTargets(failed)
+	return posted, event
+}
+
+// ownsPerAppTenant reports whether tenantName is one CIS should manage in
+// PerAppMode. An empty PerAppTenants list means every tenant is owned.
+func (am *AS3Manager) ownsPerAppTenant(tenantName string) bool {
+	if len(am.PerAppTenants) == 0 {
+		return true
+	}
+	for _, t := range am.PerAppTenants {
+		if t == tenantName {
+			return true
+		}
+	}
+	return false
+}
+
+// mergedPerAppTenants combines cfg.resourceConfig with every configmap's
+// tenants into a single as3ADC, the same way getUnifiedDeclaration folds
+// configmaps into adc for the bulk path. Without this, PerAppMode would
+// only ever see Route-derived tenants and silently never post or delete
+// ConfigMap-sourced Applications.
+func mergedPerAppTenants(cfg AS3Config) as3ADC {
+	merged := make(as3ADC, len(cfg.resourceConfig))
+	for tenantName, tenant := range cfg.resourceConfig {
+		merged[tenantName] = tenant
+	}
+	for _, cm := range cfg.configmaps {
+		for tenantName, tenant := range cm.config {
+			merged[tenantName] = tenant
+		}
+	}
+	return merged
+}
+
+// getDeletedPerApps returns the tenant/application pairs that were present
+// in the previously posted declaration but have dropped out of curTenantMap.
+func (am *AS3Manager) getDeletedPerApps(curTenantMap as3ADC) map[string][]string {
+	deletedApps := make(map[string][]string)
+	for tenantName, tenant := range mergedPerAppTenants(am.as3ActiveConfig) {
+		if !am.ownsPerAppTenant(tenantName) || !am.tenantAllowed(tenantName) {
+			continue
+		}
+		prevTenantDecl, ok := tenant.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		curTenantDecl, _ := curTenantMap[tenantName].(map[string]interface{})
+		for appName := range prevTenantDecl {
+			if appName == as3class {
+				continue
+			}
+			if _, found := curTenantDecl[appName]; !found {
+				deletedApps[tenantName] = append(deletedApps[tenantName], appName)
+			}
+		}
+	}
+	return deletedApps
 }
 
 func (cfg *AS3Config) updateConfig(newAS3Cfg AS3Config) {
@@ -231,6 +680,10 @@ func (am *AS3Manager) getUnifiedDeclaration(cfg *AS3Config) as3Declaration {
 	_ = json.Unmarshal([]byte(baseAS3ConfigTemplate), &as3Obj)
 	adc, _ := as3Obj["declaration"].(map[string]interface{})
 
+	if am.DryRun {
+		as3Obj["action"] = "dry-run"
+	}
+
 	for tenantName, tenant := range cfg.resourceConfig {
 		adc[tenantName] = tenant
 	}
@@ -309,23 +762,190 @@ func (am *AS3Manager) getDeletedTenants(curTenantMap map[string]interface{}) []s
 
 	for _, tnt := range prevTenants {
 		if _, found := curTenantMap[tnt]; !found {
+			if !am.tenantAllowed(tnt) {
+				// tnt is outside TenantFilter: it isn't ours to delete, even
+				// though it vanished from our own declaration.
+				continue
+			}
 			deletedTenants = append(deletedTenants, tnt)
 		}
 	}
 	return deletedTenants
 }
 
+// tenantAllowed reports whether tenant is one CIS is permitted to manage.
+// With no TenantFilter configured, every tenant is allowed, preserving the
+// historical behavior of deriving ownership from the current declaration.
+func (am *AS3Manager) tenantAllowed(tenant string) bool {
+	if len(am.TenantFilter) == 0 {
+		return true
+	}
+	for _, t := range am.TenantFilter {
+		if t == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// intersectTenantFilter narrows tenants down to those also present in
+// TenantFilter, so a misbehaving declaration can never cause CIS to POST to
+// a tenant another tool owns.
+func (am *AS3Manager) intersectTenantFilter(tenants []string) []string {
+	if len(am.TenantFilter) == 0 {
+		return tenants
+	}
+	var filtered []string
+	for _, t := range tenants {
+		if am.tenantAllowed(t) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
 // Method to delete any AS3 partition
 func (am *AS3Manager) DeleteAS3Partition(partition string) (bool, string) {
+	if am.PerAppMode {
+		// A bulk "wipe this Tenant" declaration would nuke every Application
+		// in partition, including ones owned by other CIS instances or tools
+		// that PerAppMode exists to leave untouched. Callers must delete
+		// individual Applications via DeleteAS3PerApp instead.
+		log.Errorf("[AS3] Refusing to delete partition %v as a whole AS3 Tenant wipe: PerAppMode is enabled, use DeleteAS3PerApp", partition)
+		return false, ""
+	}
+	if !am.tenantAllowed(partition) {
+		log.Errorf("[AS3] Refusing to delete partition %v: it is outside TenantFilter", partition)
+		return false, ""
+	}
 	emptyAS3Declaration := am.getEmptyAs3Declaration(partition)
-	return am.PostManager.postConfig(string(emptyAS3Declaration), nil)
+	posted, event, failed := am.postToTargets(am.PostManagers, func(pm *PostManager) (bool, string) {
+		decl := emptyAS3Declaration
+		if pm.DefaultPartition != "" && partition != "" {
+			decl = am.rekeyPartition(decl, pm.DefaultPartition)
+		}
+		return pm.postConfig(string(decl), nil)
+	})
+	am.failedTargets = failed
+	return posted, event
+}
+
+// DeleteAS3PerApp removes a single Application from a shared Tenant,
+// leaving the rest of the Tenant (owned by other CIS instances or tools)
+// untouched. Only used when PerAppMode is enabled.
+func (am *AS3Manager) DeleteAS3PerApp(tenant, appName string) (bool, string) {
+	if !am.ownsPerAppTenant(tenant) || !am.tenantAllowed(tenant) {
+		// Same safety gate getDeletedPerApps applies automatically: tenant
+		// isn't one CIS owns in PerAppMode or is outside TenantFilter, so
+		// deleting an Application out of it isn't ours to do.
+		log.Errorf("[AS3] Refusing to delete %v/%v: tenant %v is not owned by this CIS instance", tenant, appName, tenant)
+		return false, ""
+	}
+	posted, event, failed := am.postToTargets(am.PostManagers, func(pm *PostManager) (bool, string) {
+		return pm.deletePerAppConfig(tenant, appName)
+	})
+	am.failedTargets = failed
+	return posted, event
 }
 
-// fetchAS3Schema ...
+// fetchAS3Schema resolves the AS3 schema to validate declarations against.
+// It tries to fetch the schema matching the BIG-IP's discovered AS3
+// version/build from SchemaBaseURL, caches whatever it fetched on disk
+// keyed by that version/build, and falls back to a cached or bundled copy
+// on network failure.
 func (am *AS3Manager) fetchAS3Schema() {
-	log.Debugf("[AS3] Validating AS3 schema with  %v", as3SchemaFileName)
-	am.As3SchemaLatest = am.SchemaLocalPath + as3SchemaFileName
-	return
+	release := am.as3Release
+	if release == "" {
+		release = defaultAS3Version + "-" + defaultAS3Build
+	}
+	cachePath := am.SchemaLocalPath + fmt.Sprintf("as3-schema-%s-cis.json", release)
+
+	log.Debugf("[AS3] Validating AS3 schema with %v", cachePath)
+
+	if schema, err := am.fetchRemoteAS3Schema(release); err == nil {
+		if werr := ioutil.WriteFile(cachePath, schema, 0644); werr != nil {
+			log.Errorf("[AS3] Unable to cache AS3 schema %v: %v", cachePath, werr)
+		}
+		am.As3SchemaLatest = cachePath
+		return
+	} else {
+		log.Debugf("[AS3] Unable to fetch remote AS3 schema for %v: %v", release, err)
+	}
+
+	if cached, err := ioutil.ReadFile(cachePath); err == nil {
+		if cerr := am.verifySchemaChecksum(release, cached); cerr != nil {
+			log.Errorf("[AS3] Discarding cached AS3 schema %v: %v", cachePath, cerr)
+		} else {
+			log.Debugf("[AS3] Using previously cached AS3 schema %v", cachePath)
+			am.As3SchemaLatest = cachePath
+			return
+		}
+	}
+
+	bundledPath := am.SchemaLocalPath + as3SchemaFileName
+	bundled, err := ioutil.ReadFile(bundledPath)
+	if err != nil {
+		log.Errorf("[AS3] Unable to read bundled AS3 schema %v: %v", bundledPath, err)
+		return
+	}
+	if cerr := am.verifySchemaChecksum(release, bundled); cerr != nil {
+		log.Errorf("[AS3] Bundled AS3 schema %v failed checksum verification, no trusted AS3 schema available: %v", bundledPath, cerr)
+		return
+	}
+
+	log.Debugf("[AS3] Falling back to bundled AS3 schema %v", as3SchemaFileName)
+	am.As3SchemaLatest = bundledPath
+}
+
+// verifySchemaChecksum checks data's SHA256 against SchemaChecksums[release].
+// A release with no pinned checksum is trusted as-is.
+func (am *AS3Manager) verifySchemaChecksum(release string, data []byte) error {
+	expected, ok := am.SchemaChecksums[release]
+	if !ok {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected {
+		return fmt.Errorf("AS3 schema %v failed checksum verification", release)
+	}
+	return nil
+}
+
+// fetchRemoteAS3Schema fetches the AS3 schema for release ("<version>-
+// <build>") from SchemaBaseURL, verifying it against SchemaChecksums when a
+// checksum for that release is pinned. It reuses a PostManager's
+// httpClient so an internal/air-gapped mirror gets the same SSLInsecure/
+// TrustedCerts TLS trust as the BIG-IP targets themselves.
+func (am *AS3Manager) fetchRemoteAS3Schema(release string) ([]byte, error) {
+	baseURL := am.SchemaBaseURL
+	if baseURL == "" {
+		baseURL = defaultAS3SchemaBaseURL
+	}
+	url := fmt.Sprintf("%s/%s/as3-schema-%s.json", baseURL, am.as3Version, release)
+
+	client := http.DefaultClient
+	if len(am.PostManagers) > 0 {
+		client = am.PostManagers[0].httpClient
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching AS3 schema %v: status %v", url, resp.StatusCode)
+	}
+
+	schema, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := am.verifySchemaChecksum(release, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
 }
 
 // configDeployer blocks on ReqChan
@@ -335,10 +955,10 @@ func (am *AS3Manager) ConfigDeployer() {
 	firstPost := true
 	am.unprocessableEntityStatus = false
 	for msgReq := range am.ReqChan {
-		if !firstPost && am.PostManager.AS3PostDelay != 0 {
+		if !firstPost && am.PostManagers[0].AS3PostDelay != 0 {
 			// Time (in seconds) that CIS waits to post the AS3 declaration to BIG-IP.
-			log.Debugf("[AS3] Delaying post to BIG-IP for %v seconds", am.PostManager.AS3PostDelay)
-			_ = <-time.After(time.Duration(am.PostManager.AS3PostDelay) * time.Second)
+			log.Debugf("[AS3] Delaying post to BIG-IP for %v seconds", am.PostManagers[0].AS3PostDelay)
+			_ = <-time.After(time.Duration(am.PostManagers[0].AS3PostDelay) * time.Second)
 		}
 
 		// After postDelay expires pick up latest declaration, if available
@@ -348,9 +968,11 @@ func (am *AS3Manager) ConfigDeployer() {
 		}
 
 		posted, event := am.postAS3Declaration(msgReq.ResourceRequest)
-		// To handle general errors
-		for !posted {
-			am.unprocessableEntityStatus = true
+		// To handle general errors, and to reconcile any targets that failed
+		// individually even though quorum was met (am.failedTargets non-empty
+		// with posted == true under quorumMajority/quorumAny).
+		for !posted || len(am.failedTargets) > 0 {
+			am.unprocessableEntityStatus = !posted
 			timeout := getTimeDurationForErrorResponse(event)
 			log.Debugf("[AS3] Error handling for event %v", event)
 			posted, event = am.postOnEventOrTimeout(timeout)
@@ -372,12 +994,41 @@ func (am *AS3Manager) postOnEventOrTimeout(timeout time.Duration) (bool, string)
 	case msgReq := <-am.ReqChan:
 		return am.postAS3Declaration(msgReq.ResourceRequest)
 	case <-time.After(timeout):
+		// Only the targets that failed last time need a retry.
+		retryTargets := am.failedTargets
+		if len(retryTargets) == 0 {
+			retryTargets = am.PostManagers
+		}
+
+		if am.PerAppMode {
+			// Retrying a per-app failure must stay scoped to per-app
+			// POSTs/deletes against retryTargets; falling through to the
+			// bulk postConfig below would overwrite the whole shared Tenant,
+			// including Applications PerAppMode exists to keep CIS from
+			// touching.
+			return am.postPerAppToTargets(am.as3ActiveConfig, am.lastDeletedPerApps, retryTargets)
+		}
+
 		var tenants []string = nil
-		if am.FilterTenants {
-			tenants = getTenants(am.as3ActiveConfig.unifiedDeclaration, true)
+		if am.FilterTenants || len(am.TenantFilter) > 0 {
+			tenants = am.intersectTenantFilter(getTenants(am.as3ActiveConfig.unifiedDeclaration, true))
 		}
 		unifiedDeclaration := string(am.as3ActiveConfig.unifiedDeclaration)
-		return am.PostManager.postConfig(unifiedDeclaration, tenants)
+
+		if am.DryRun {
+			// Retrying a dry-run failure must stay preview-only; never fall
+			// through to the applying postConfig below.
+			posted, diff := am.PostManagers[0].postDryRunConfig(unifiedDeclaration, tenants)
+			am.lastDryRunResult = DryRunResult{IsResponseSuccessful: posted, Diff: diff}
+			am.postDryRunResponse(am.lastDryRunResult)
+			return posted, diff
+		}
+
+		posted, event, failed := am.postToTargets(retryTargets, func(pm *PostManager) (bool, string) {
+			return pm.postConfig(unifiedDeclaration, tenants)
+		})
+		am.failedTargets = failed
+		return posted, event
 	}
 }
 
@@ -401,7 +1052,10 @@ func (am *AS3Manager) postAgentResponse(msgRsp MessageResponse) {
 // compatible with BIG-IP, it will return with error if any one of the
 // requirements are not met
 func (am *AS3Manager) IsBigIPAppServicesAvailable() error {
-	version, build, err := am.PostManager.GetBigipAS3Version()
+	// The primary target's AS3 version governs the schema CIS validates
+	// against; HA peers are expected to run the same AS3 version.
+	version, build, err := am.PostManagers[0].GetBigipAS3Version()
+	previousRelease := am.as3Release
 	am.as3Version = version
 	as3Build := build
 	am.as3Release = am.as3Version + "-" + as3Build
@@ -417,6 +1071,9 @@ func (am *AS3Manager) IsBigIPAppServicesAvailable() error {
 	}
 	if bigIPAS3Version >= as3SupportedVersion && bigIPAS3Version <= as3Version {
 		log.Debugf("[AS3] BIGIP is serving with AS3 version: %v", version)
+		if am.as3Release != previousRelease {
+			am.fetchAS3Schema()
+		}
 		return nil
 	}
 
@@ -425,10 +1082,13 @@ func (am *AS3Manager) IsBigIPAppServicesAvailable() error {
 		as3Build := defaultAS3Build
 		am.as3Release = am.as3Version + "-" + as3Build
 		log.Debugf("[AS3] BIGIP is serving with AS3 version: %v", bigIPAS3Version)
+		if am.as3Release != previousRelease {
+			am.fetchAS3Schema()
+		}
 		return nil
 	}
 
 	return fmt.Errorf("CIS versions >= 2.0 are compatible with AS3 versions >= %v. "+
 		"Upgrade AS3 version in BIGIP from %v to %v or above.", as3SupportedVersion,
 		bigIPAS3Version, as3SupportedVersion)
-}
\ No newline at end of file
+}
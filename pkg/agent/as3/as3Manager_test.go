@@ -0,0 +1,203 @@
+/*-
+ * Copyright (c) 2016-2020, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package as3
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMeetsQuorum(t *testing.T) {
+	tests := []struct {
+		name         string
+		quorum       string
+		successCount int
+		total        int
+		want         bool
+	}{
+		{"all succeed under quorumAll", quorumAll, 3, 3, true},
+		{"one failure under quorumAll", quorumAll, 2, 3, false},
+		{"majority succeed under quorumMajority", quorumMajority, 2, 3, true},
+		{"exact half fails quorumMajority", quorumMajority, 1, 2, false},
+		{"one success under quorumAny", quorumAny, 1, 3, true},
+		{"no success under quorumAny", quorumAny, 0, 3, false},
+		{"unknown quorum defaults to quorumAll", "bogus", 2, 3, false},
+		{"no targets always meets quorum", quorumAll, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := &AS3Manager{quorum: tt.quorum}
+			if got := am.meetsQuorum(tt.successCount, tt.total); got != tt.want {
+				t.Errorf("meetsQuorum(%v, %v) with quorum %q = %v, want %v",
+					tt.successCount, tt.total, tt.quorum, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRekeyPartition(t *testing.T) {
+	tests := []struct {
+		name      string
+		decl      as3Declaration
+		partition string
+		wantKey   string
+		wantDrop  string
+	}{
+		{
+			name:      "single Tenant is renamed to partition",
+			decl:      as3Declaration(`{"class":"AS3","declaration":{"class":"ADC","schemaVersion":"3.0.0","myTenant":{"class":"Tenant"}}}`),
+			partition: "myPartition",
+			wantKey:   "myPartition",
+			wantDrop:  "myTenant",
+		},
+		{
+			name:      "already under the target partition is left unchanged",
+			decl:      as3Declaration(`{"class":"AS3","declaration":{"class":"ADC","schemaVersion":"3.0.0","myPartition":{"class":"Tenant"}}}`),
+			partition: "myPartition",
+			wantKey:   "myPartition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := &AS3Manager{}
+			got := am.rekeyPartition(tt.decl, tt.partition)
+			if !getTenantPresent(t, got, tt.wantKey) {
+				t.Errorf("rekeyPartition(%v, %v) = %v, missing Tenant %v", tt.decl, tt.partition, got, tt.wantKey)
+			}
+			if tt.wantDrop != "" && getTenantPresent(t, got, tt.wantDrop) {
+				t.Errorf("rekeyPartition(%v, %v) = %v, still has old Tenant %v", tt.decl, tt.partition, got, tt.wantDrop)
+			}
+		})
+	}
+
+	t.Run("multiple Tenants are posted unchanged", func(t *testing.T) {
+		am := &AS3Manager{}
+		decl := as3Declaration(`{"class":"AS3","declaration":{"class":"ADC","schemaVersion":"3.0.0","tenantA":{"class":"Tenant"},"tenantB":{"class":"Tenant"}}}`)
+		got := am.rekeyPartition(decl, "myPartition")
+		if got != decl {
+			t.Errorf("rekeyPartition with multiple Tenants = %v, want unchanged %v", got, decl)
+		}
+	})
+}
+
+// getTenantPresent reports whether decl's ADC contains a key named tenant.
+func getTenantPresent(t *testing.T, decl as3Declaration, tenant string) bool {
+	t.Helper()
+	tenants := getTenants(decl, false)
+	for _, tnt := range tenants {
+		if tnt == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIntersectTenantFilter(t *testing.T) {
+	tests := []struct {
+		name         string
+		tenantFilter []string
+		tenants      []string
+		want         []string
+	}{
+		{
+			name:         "no TenantFilter passes every tenant through",
+			tenantFilter: nil,
+			tenants:      []string{"tenantA", "tenantB"},
+			want:         []string{"tenantA", "tenantB"},
+		},
+		{
+			name:         "TenantFilter narrows to the allowlist",
+			tenantFilter: []string{"tenantA"},
+			tenants:      []string{"tenantA", "tenantB"},
+			want:         []string{"tenantA"},
+		},
+		{
+			name:         "tenant outside the allowlist is dropped entirely",
+			tenantFilter: []string{"tenantC"},
+			tenants:      []string{"tenantA", "tenantB"},
+			want:         nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := &AS3Manager{TenantFilter: tt.tenantFilter}
+			got := am.intersectTenantFilter(tt.tenants)
+			if !equalStrings(got, tt.want) {
+				t.Errorf("intersectTenantFilter(%v) with TenantFilter %v = %v, want %v",
+					tt.tenants, tt.tenantFilter, got, tt.want)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestVerifySchemaChecksum(t *testing.T) {
+	data := []byte(`{"schema": "example"}`)
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		release string
+		data    []byte
+		sums    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "matching checksum passes",
+			release: "3.50.0-5",
+			data:    data,
+			sums:    map[string]string{"3.50.0-5": checksum},
+			wantErr: false,
+		},
+		{
+			name:    "mismatched checksum fails",
+			release: "3.50.0-5",
+			data:    data,
+			sums:    map[string]string{"3.50.0-5": "0000000000000000000000000000000000000000000000000000000000000000"},
+			wantErr: true,
+		},
+		{
+			name:    "release with no pinned checksum is trusted as-is",
+			release: "3.50.0-5",
+			data:    data,
+			sums:    map[string]string{"3.40.0-1": checksum},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := &AS3Manager{SchemaChecksums: tt.sums}
+			err := am.verifySchemaChecksum(tt.release, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifySchemaChecksum(%v, ...) error = %v, wantErr %v", tt.release, err, tt.wantErr)
+			}
+		})
+	}
+}
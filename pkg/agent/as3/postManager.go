@@ -0,0 +1,374 @@
+/*-
+ * Copyright (c) 2016-2020, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package as3
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/F5Networks/k8s-bigip-ctlr/pkg/resource"
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+)
+
+const (
+	as3DeclareEndpoint = "/mgmt/shared/appsvcs/declare"
+	as3InfoEndpoint    = "/mgmt/shared/appsvcs/info"
+	as3PerAppEndpoint  = as3DeclareEndpoint + "/%s/applications/%s"
+
+	as3LoginEndpoint   = "/mgmt/shared/authn/login"
+	as3TokensEndpoint  = "/mgmt/shared/authz/tokens"
+	as3AuthTokenHeader = "X-F5-Auth-Token"
+
+	// AuthModeBasic sends the BIG-IP username/password on every request.
+	AuthModeBasic = "basic"
+	// AuthModeToken exchanges credentials for an iControl auth token once,
+	// then refreshes it transparently as it nears expiry.
+	AuthModeToken = "token"
+
+	// tokenRefreshMargin is how far ahead of its stated timeout a token is
+	// proactively refreshed.
+	tokenRefreshMargin = 30 * time.Second
+)
+
+// BIGIPTarget describes one device CIS pushes identical AS3 declarations
+// to, e.g. a member of a device-group or an active/standby pair.
+type BIGIPTarget struct {
+	BIGIPURL      string
+	BIGIPUsername string
+	BIGIPPassword string
+	TrustedCerts  string
+	// DefaultPartition, when set, renames the single Tenant in every
+	// declaration posted to this device to this partition name instead of
+	// the Tenant name CIS derived the declaration with, so blue/green
+	// partitions across devices are supported.
+	DefaultPartition string
+}
+
+// PostParams holds the fields needed to construct a PostManager.
+type PostParams struct {
+	BIGIPUsername string
+	BIGIPPassword string
+	BIGIPURL      string
+	TrustedCerts  string
+	SSLInsecure   bool
+	AS3PostDelay  int
+	LogResponse   bool
+	// AuthMode selects between AuthModeBasic (default) and AuthModeToken.
+	AuthMode string
+	// ExternalAuthProviderName is the name of the remote auth provider
+	// (LDAP/RADIUS/TACACS) to authenticate against in AuthModeToken. Left
+	// empty, BIG-IP's local "tmos" provider is used.
+	ExternalAuthProviderName string
+	// DefaultPartition overrides the partition declarations are written
+	// under on this target, see BIGIPTarget.DefaultPartition.
+	DefaultPartition string
+}
+
+// authToken is a cached F5 iControl auth token obtained via AuthModeToken.
+type authToken struct {
+	token     string
+	timeout   int
+	createdAt time.Time
+}
+
+// PostManager POSTs AS3 declarations to BIG-IP over the iControl REST API.
+type PostManager struct {
+	PostParams
+	httpClient *http.Client
+	token      *authToken
+}
+
+// NewPostManager creates a PostManager ready to post AS3 declarations.
+func NewPostManager(params PostParams) *PostManager {
+	pm := &PostManager{
+		PostParams: params,
+	}
+	if pm.AuthMode == "" {
+		pm.AuthMode = AuthModeBasic
+	}
+	rootCAs, _ := x509.SystemCertPool()
+	if rootCAs == nil {
+		rootCAs = x509.NewCertPool()
+	}
+	rootCAs.AppendCertsFromPEM([]byte(params.TrustedCerts))
+	pm.httpClient = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: params.SSLInsecure,
+				RootCAs:            rootCAs,
+			},
+		},
+	}
+	return pm
+}
+
+// declareURL returns the Tenant-scoped AS3 declare endpoint, optionally
+// filtered down to a subset of tenants.
+func (pm *PostManager) declareURL(tenants []string) string {
+	url := fmt.Sprintf("https://%s%s", pm.BIGIPURL, as3DeclareEndpoint)
+	if len(tenants) > 0 {
+		url = fmt.Sprintf("%s/%s", url, strings.Join(tenants, ","))
+	}
+	return url
+}
+
+// authenticate attaches credentials to req, using a cached/refreshed
+// iControl token in AuthModeToken or basic auth otherwise.
+func (pm *PostManager) authenticate(req *http.Request) error {
+	if pm.AuthMode != AuthModeToken {
+		req.SetBasicAuth(pm.BIGIPUsername, pm.BIGIPPassword)
+		return nil
+	}
+	if err := pm.ensureToken(); err != nil {
+		return err
+	}
+	req.Header.Set(as3AuthTokenHeader, pm.token.token)
+	return nil
+}
+
+// ensureToken makes sure PostManager holds a valid, non-expiring-soon
+// iControl auth token, logging in or refreshing as needed.
+func (pm *PostManager) ensureToken() error {
+	if pm.token == nil {
+		return pm.login()
+	}
+	remaining := float64(pm.token.timeout) - time.Since(pm.token.createdAt).Seconds()
+	if remaining <= tokenRefreshMargin.Seconds() {
+		if err := pm.refreshToken(); err != nil {
+			// The token may already be gone; fall back to a fresh login.
+			return pm.login()
+		}
+	}
+	return nil
+}
+
+// login exchanges BIG-IP credentials for an X-F5-Auth-Token, optionally
+// against an external auth provider (LDAP/RADIUS/TACACS).
+func (pm *PostManager) login() error {
+	loginBody := map[string]string{
+		"username": pm.BIGIPUsername,
+		"password": pm.BIGIPPassword,
+	}
+	if pm.ExternalAuthProviderName != "" {
+		loginBody["loginProviderName"] = pm.ExternalAuthProviderName
+	}
+	data, err := json.Marshal(loginBody)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://%s%s", pm.BIGIPURL, as3LoginEndpoint)
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token login failed with status %v", resp.StatusCode)
+	}
+
+	var loginResp struct {
+		Token struct {
+			Token   string `json:"token"`
+			Timeout int    `json:"timeout"`
+		} `json:"token"`
+	}
+	if err := json.Unmarshal(body, &loginResp); err != nil {
+		return err
+	}
+	pm.token = &authToken{
+		token:     loginResp.Token.Token,
+		timeout:   loginResp.Token.Timeout,
+		createdAt: time.Now(),
+	}
+	return nil
+}
+
+// refreshToken extends the lifetime of the cached token in place.
+func (pm *PostManager) refreshToken() error {
+	url := fmt.Sprintf("https://%s%s/%s", pm.BIGIPURL, as3TokensEndpoint, pm.token.token)
+	data, err := json.Marshal(map[string]interface{}{"timeout": pm.token.timeout})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(as3AuthTokenHeader, pm.token.token)
+
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token refresh failed with status %v", resp.StatusCode)
+	}
+	pm.token.createdAt = time.Now()
+	return nil
+}
+
+// doRequest issues req against BIG-IP and interprets the response as an AS3
+// post/delete result, retrying once on a stale token. When dryRun is true,
+// the response body is returned as the diff/event string on success too
+// (instead of responseStatusOk), matching what postDryRunConfig's caller
+// needs to preview.
+func (pm *PostManager) doRequest(req *http.Request, dryRun bool) (bool, string) {
+	if err := pm.authenticate(req); err != nil {
+		log.Errorf("[AS3] Authentication error: %v", err)
+		return false, ""
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		log.Errorf("[AS3] error while posting to %v: %v", req.URL, err)
+		return false, ""
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && pm.AuthMode == AuthModeToken {
+		resp.Body.Close()
+		pm.token = nil
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return false, ""
+			}
+			req.Body = ioutil.NopCloser(body)
+		}
+		if err := pm.authenticate(req); err != nil {
+			log.Errorf("[AS3] Re-authentication error: %v", err)
+			return false, ""
+		}
+		resp, err = pm.httpClient.Do(req)
+		if err != nil {
+			log.Errorf("[AS3] error while posting to %v: %v", req.URL, err)
+			return false, ""
+		}
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if pm.LogResponse {
+		log.Debugf("[AS3] Response from BIG-IP: %v", string(body))
+	}
+
+	ok := resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted
+	if ok && !dryRun {
+		return true, responseStatusOk
+	}
+	return ok, string(body)
+}
+
+// postConfig POSTs a full AS3 declaration, optionally scoped to tenants.
+func (pm *PostManager) postConfig(decl string, tenants []string) (bool, string) {
+	req, err := http.NewRequest("POST", pm.declareURL(tenants), bytes.NewBufferString(decl))
+	if err != nil {
+		log.Errorf("[AS3] Creating new HTTP request error: %v ", err)
+		return false, ""
+	}
+	return pm.doRequest(req, false)
+}
+
+// postDryRunConfig POSTs decl (already carrying "action":"dry-run") and
+// returns BIG-IP's diff response via doRequest, so a dry-run post still
+// benefits from doRequest's 401-retry-on-stale-token handling.
+func (pm *PostManager) postDryRunConfig(decl string, tenants []string) (bool, string) {
+	req, err := http.NewRequest("POST", pm.declareURL(tenants), bytes.NewBufferString(decl))
+	if err != nil {
+		log.Errorf("[AS3] Creating new HTTP request error: %v ", err)
+		return false, ""
+	}
+	return pm.doRequest(req, true)
+}
+
+// perAppURL returns the per-application AS3 declare endpoint for a single
+// Application owned by CIS inside a shared Tenant.
+func (pm *PostManager) perAppURL(tenant, appName string) string {
+	return fmt.Sprintf("https://%s"+as3PerAppEndpoint, pm.BIGIPURL, tenant, appName)
+}
+
+// postPerAppConfig POSTs a single Application sub-declaration under tenant,
+// leaving the rest of the shared Tenant untouched.
+func (pm *PostManager) postPerAppConfig(tenant, appName, subDecl string) (bool, string) {
+	req, err := http.NewRequest("POST", pm.perAppURL(tenant, appName), bytes.NewBufferString(subDecl))
+	if err != nil {
+		log.Errorf("[AS3] Creating new HTTP request error: %v ", err)
+		return false, ""
+	}
+	return pm.doRequest(req, false)
+}
+
+// deletePerAppConfig removes a single Application from a shared Tenant via
+// the per-application AS3 endpoint.
+func (pm *PostManager) deletePerAppConfig(tenant, appName string) (bool, string) {
+	req, err := http.NewRequest("DELETE", pm.perAppURL(tenant, appName), nil)
+	if err != nil {
+		log.Errorf("[AS3] Creating new HTTP request error: %v ", err)
+		return false, ""
+	}
+	return pm.doRequest(req, false)
+}
+
+// GetBigipAS3Version returns the AS3 version and build installed on BIG-IP.
+func (pm *PostManager) GetBigipAS3Version() (string, string, error) {
+	url := fmt.Sprintf("https://%s%s", pm.BIGIPURL, as3InfoEndpoint)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if err := pm.authenticate(req); err != nil {
+		return "", "", err
+	}
+
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("could not reach AS3 info endpoint, status: %v", resp.StatusCode)
+	}
+
+	var info struct {
+		Version string `json:"version"`
+		Release string `json:"release"`
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", "", err
+	}
+	return info.Version, info.Release, nil
+}
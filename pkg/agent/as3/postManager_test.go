@@ -0,0 +1,189 @@
+/*-
+ * Copyright (c) 2016-2020, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package as3
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenTestServer starts a TLS test server handling login and token
+// refresh, counting how many times each endpoint is hit.
+func newTokenTestServer(t *testing.T) (server *httptest.Server, loginCount, refreshCount *int32) {
+	t.Helper()
+	loginCount = new(int32)
+	refreshCount = new(int32)
+	mux := http.NewServeMux()
+	mux.HandleFunc(as3LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(loginCount, 1)
+		fmt.Fprint(w, `{"token":{"token":"tok1","timeout":100}}`)
+	})
+	mux.HandleFunc(as3TokensEndpoint+"/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(refreshCount, 1)
+		w.WriteHeader(http.StatusOK)
+	})
+	server = httptest.NewTLSServer(mux)
+	return server, loginCount, refreshCount
+}
+
+func newTokenTestPostManager(server *httptest.Server) *PostManager {
+	pm := NewPostManager(PostParams{
+		BIGIPURL:    strings.TrimPrefix(server.URL, "https://"),
+		SSLInsecure: true,
+		AuthMode:    AuthModeToken,
+	})
+	pm.httpClient = server.Client()
+	return pm
+}
+
+func TestEnsureTokenLogsInWhenNoToken(t *testing.T) {
+	server, loginCount, _ := newTokenTestServer(t)
+	defer server.Close()
+	pm := newTokenTestPostManager(server)
+
+	if err := pm.ensureToken(); err != nil {
+		t.Fatalf("ensureToken() error = %v", err)
+	}
+	if pm.token == nil || pm.token.token != "tok1" {
+		t.Fatalf("ensureToken() left token = %+v, want a tok1 token", pm.token)
+	}
+	if got := atomic.LoadInt32(loginCount); got != 1 {
+		t.Errorf("login endpoint hit %v times, want 1", got)
+	}
+}
+
+func TestEnsureTokenRefreshMargin(t *testing.T) {
+	tests := []struct {
+		name          string
+		remaining     time.Duration
+		wantRefreshed bool
+	}{
+		{"well inside timeout skips refresh", tokenRefreshMargin + 10*time.Second, false},
+		{"exactly at the margin refreshes", tokenRefreshMargin, true},
+		{"past the margin refreshes", tokenRefreshMargin - 10*time.Second, true},
+		{"already expired refreshes", -10 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, refreshCount := newTokenTestServer(t)
+			defer server.Close()
+			pm := newTokenTestPostManager(server)
+
+			timeout := 100
+			createdAt := time.Now().Add(-(time.Duration(timeout)*time.Second - tt.remaining))
+			pm.token = &authToken{token: "cached", timeout: timeout, createdAt: createdAt}
+
+			if err := pm.ensureToken(); err != nil {
+				t.Fatalf("ensureToken() error = %v", err)
+			}
+			gotRefreshed := atomic.LoadInt32(refreshCount) > 0
+			if gotRefreshed != tt.wantRefreshed {
+				t.Errorf("ensureToken() refreshed = %v, want %v", gotRefreshed, tt.wantRefreshed)
+			}
+			if !tt.wantRefreshed && pm.token.createdAt != createdAt {
+				t.Errorf("ensureToken() updated createdAt %v when no refresh was expected", pm.token.createdAt)
+			}
+		})
+	}
+}
+
+func TestEnsureTokenFallsBackToLoginWhenRefreshFails(t *testing.T) {
+	mux := http.NewServeMux()
+	var loginCount int32
+	mux.HandleFunc(as3LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&loginCount, 1)
+		fmt.Fprint(w, `{"token":{"token":"tok2","timeout":100}}`)
+	})
+	mux.HandleFunc(as3TokensEndpoint+"/", func(w http.ResponseWriter, r *http.Request) {
+		// The cached token is already gone server-side; refresh fails.
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	pm := newTokenTestPostManager(server)
+	pm.token = &authToken{token: "stale", timeout: 100, createdAt: time.Now().Add(-99 * time.Second)}
+
+	if err := pm.ensureToken(); err != nil {
+		t.Fatalf("ensureToken() error = %v", err)
+	}
+	if pm.token == nil || pm.token.token != "tok2" {
+		t.Fatalf("ensureToken() after failed refresh left token = %+v, want a fresh tok2 login", pm.token)
+	}
+	if got := atomic.LoadInt32(&loginCount); got != 1 {
+		t.Errorf("login endpoint hit %v times, want 1", got)
+	}
+}
+
+func TestDoRequestRetriesOnceOnStaleToken(t *testing.T) {
+	mux := http.NewServeMux()
+	var loginCount, declareCount int32
+	mux.HandleFunc(as3LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&loginCount, 1)
+		fmt.Fprint(w, `{"token":{"token":"tok3","timeout":100}}`)
+	})
+	mux.HandleFunc(as3DeclareEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&declareCount, 1) == 1 {
+			// BIG-IP considers the cached token stale; doRequest must
+			// re-authenticate and retry exactly once.
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	pm := newTokenTestPostManager(server)
+	pm.token = &authToken{token: "stale", timeout: 100, createdAt: time.Now()}
+
+	posted, event := pm.postConfig(`{"class":"AS3"}`, nil)
+	if !posted || event != responseStatusOk {
+		t.Fatalf("postConfig() = (%v, %v), want (true, %v)", posted, event, responseStatusOk)
+	}
+	if got := atomic.LoadInt32(&declareCount); got != 2 {
+		t.Errorf("declare endpoint hit %v times, want 2 (initial + retry)", got)
+	}
+	if got := atomic.LoadInt32(&loginCount); got != 1 {
+		t.Errorf("login endpoint hit %v times, want 1 (re-authentication after 401)", got)
+	}
+	if pm.token == nil || pm.token.token != "tok3" {
+		t.Errorf("doRequest left token = %+v, want the freshly logged-in tok3", pm.token)
+	}
+}
+
+func TestDoRequestFailsAfterRepeated401(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(as3LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token":{"token":"tok4","timeout":100}}`)
+	})
+	mux.HandleFunc(as3DeclareEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	pm := newTokenTestPostManager(server)
+	pm.token = &authToken{token: "stale", timeout: 100, createdAt: time.Now()}
+
+	posted, _ := pm.postConfig(`{"class":"AS3"}`, nil)
+	if posted {
+		t.Errorf("postConfig() = (%v, _), want false when BIG-IP keeps returning 401", posted)
+	}
+}
@@ -0,0 +1,228 @@
+/*-
+ * Copyright (c) 2016-2020, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package do implements a Declarative Onboarding sibling to pkg/agent/as3,
+// letting CIS onboard a BIG-IP (VLANs, self-IPs, NTP, DNS, licensing) from a
+// single designated ConfigMap instead of a separate tool.
+package do
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+)
+
+const (
+	// DOConfigMapLabel marks the ConfigMap CIS watches for a Declarative
+	// Onboarding declaration.
+	DOConfigMapLabel = "cis.f5.com/do-config=true"
+	doSchemaFileName = "do-schema.json"
+)
+
+// DORequest carries a Declarative Onboarding declaration from the ConfigMap
+// informer to the DOManager's deployer loop.
+type DORequest struct {
+	Declaration string
+}
+
+// DOResponse reports the outcome of a DO declaration post back to whoever
+// is listening on RspChan.
+type DOResponse struct {
+	IsResponseSuccessful bool
+	Message              string
+}
+
+// Params struct to allow NewDOManager to receive all or only specific
+// parameters, mirroring as3.Params.
+type Params struct {
+	BIGIPUsername   string
+	BIGIPPassword   string
+	BIGIPURL        string
+	TrustedCerts    string
+	SSLInsecure     bool
+	SchemaLocalPath string
+	RspChan         chan interface{}
+}
+
+// DOManager holds all the Declarative Onboarding orchestration specific
+// config, modeled on as3.AS3Manager.
+type DOManager struct {
+	schemaLocalPath string
+	// DOSchemaLatest is the path of the schema used to validate declarations
+	// before they're posted.
+	DOSchemaLatest string
+	PostManager    *PostManager
+	ReqChan        chan DORequest
+	RspChan        chan interface{}
+	activeDecl     string
+}
+
+// NewDOManager creates and returns a new DOManager that meets the Manager
+// interface.
+func NewDOManager(params *Params) *DOManager {
+	dm := &DOManager{
+		schemaLocalPath: params.SchemaLocalPath,
+		RspChan:         params.RspChan,
+		ReqChan:         make(chan DORequest),
+		PostManager: NewPostManager(PostParams{
+			BIGIPUsername: params.BIGIPUsername,
+			BIGIPPassword: params.BIGIPPassword,
+			BIGIPURL:      params.BIGIPURL,
+			TrustedCerts:  params.TrustedCerts,
+			SSLInsecure:   params.SSLInsecure,
+		}),
+	}
+	dm.fetchDOSchema()
+	return dm
+}
+
+// fetchDOSchema resolves the bundled DO schema, same local-schema pattern
+// as as3.AS3Manager.fetchAS3Schema.
+func (dm *DOManager) fetchDOSchema() {
+	log.Debugf("[DO] Validating DO schema with %v", doSchemaFileName)
+	dm.DOSchemaLatest = dm.schemaLocalPath + doSchemaFileName
+}
+
+// validateDODeclaration checks decl against the bundled DO schema.
+func (dm *DOManager) validateDODeclaration(decl string) bool {
+	schema, err := ioutil.ReadFile(dm.DOSchemaLatest)
+	if err != nil {
+		log.Errorf("[DO] Unable to read DO schema %v: %v", dm.DOSchemaLatest, err)
+		return true
+	}
+	var schemaDoc map[string]interface{}
+	if err := json.Unmarshal(schema, &schemaDoc); err != nil {
+		log.Errorf("[DO] Unable to parse DO schema: %v", err)
+		return true
+	}
+	var declDoc interface{}
+	if err := json.Unmarshal([]byte(decl), &declDoc); err != nil {
+		log.Errorf("[DO] Invalid DO declaration JSON: %v", err)
+		return false
+	}
+	if err := validateAgainstSchema(schemaDoc, declDoc); err != nil {
+		log.Errorf("[DO] DO declaration failed schema validation: %v", err)
+		return false
+	}
+	return true
+}
+
+// validateAgainstSchema is a minimal, dependency-free JSON Schema check:
+// it enforces "type", "required" and "properties", recursing into nested
+// objects. It isn't a complete JSON Schema implementation, but it rejects a
+// declaration that's missing a field the schema requires or that uses the
+// wrong type for one.
+func validateAgainstSchema(schema map[string]interface{}, doc interface{}) error {
+	if schemaType, ok := schema["type"].(string); ok {
+		if err := checkSchemaType(schemaType, doc); err != nil {
+			return err
+		}
+	}
+
+	docMap, isObject := doc.(map[string]interface{})
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		if !isObject {
+			return fmt.Errorf("expected an object to check required properties")
+		}
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				continue
+			}
+			if _, found := docMap[name]; !found {
+				return fmt.Errorf("missing required property %q", name)
+			}
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok && isObject {
+		for name, propSchema := range properties {
+			propValue, found := docMap[name]
+			if !found {
+				continue
+			}
+			propSchemaMap, ok := propSchema.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err := validateAgainstSchema(propSchemaMap, propValue); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkSchemaType reports an error if doc's JSON-decoded type doesn't
+// match the JSON Schema primitive type name schemaType.
+func checkSchemaType(schemaType string, doc interface{}) error {
+	switch schemaType {
+	case "object":
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return fmt.Errorf("expected an object")
+		}
+	case "array":
+		if _, ok := doc.([]interface{}); !ok {
+			return fmt.Errorf("expected an array")
+		}
+	case "string":
+		if _, ok := doc.(string); !ok {
+			return fmt.Errorf("expected a string")
+		}
+	case "number", "integer":
+		if _, ok := doc.(float64); !ok {
+			return fmt.Errorf("expected a number")
+		}
+	case "boolean":
+		if _, ok := doc.(bool); !ok {
+			return fmt.Errorf("expected a boolean")
+		}
+	}
+	return nil
+}
+
+// ConfigDeployer blocks on ReqChan and, whenever it gets unblocked, posts
+// the DO declaration to BIG-IP, mirroring AS3Manager.ConfigDeployer.
+func (dm *DOManager) ConfigDeployer() {
+	for req := range dm.ReqChan {
+		if !dm.validateDODeclaration(req.Declaration) {
+			dm.postResponse(false, "DO declaration failed schema validation")
+			continue
+		}
+		if req.Declaration == dm.activeDecl {
+			continue
+		}
+		log.Debugf("[DO] Posting Declarative Onboarding declaration")
+		ok, msg := dm.PostManager.postDODeclaration(req.Declaration)
+		if ok {
+			dm.activeDecl = req.Declaration
+		}
+		dm.postResponse(ok, msg)
+	}
+}
+
+func (dm *DOManager) postResponse(ok bool, msg string) {
+	rsp := DOResponse{IsResponseSuccessful: ok, Message: msg}
+	select {
+	case dm.RspChan <- rsp:
+	case <-dm.RspChan:
+		dm.RspChan <- rsp
+	}
+}
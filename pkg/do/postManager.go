@@ -0,0 +1,139 @@
+/*-
+ * Copyright (c) 2016-2020, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package do
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/pkg/vlogger"
+)
+
+const (
+	doDeclareEndpoint = "/mgmt/shared/declarative-onboarding"
+	doTaskEndpoint    = doDeclareEndpoint + "/task"
+	doPollInterval    = 2 * time.Second
+	doPollTimeout     = 2 * time.Minute
+)
+
+// PostParams holds the fields needed to construct a PostManager.
+type PostParams struct {
+	BIGIPUsername string
+	BIGIPPassword string
+	BIGIPURL      string
+	TrustedCerts  string
+	SSLInsecure   bool
+}
+
+// PostManager POSTs Declarative Onboarding declarations to BIG-IP over the
+// iControl REST API, polling the async task until it completes.
+type PostManager struct {
+	PostParams
+	httpClient *http.Client
+}
+
+// NewPostManager creates a PostManager ready to post DO declarations.
+func NewPostManager(params PostParams) *PostManager {
+	pm := &PostManager{
+		PostParams: params,
+	}
+	pm.httpClient = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: params.SSLInsecure},
+		},
+	}
+	return pm
+}
+
+// postDODeclaration POSTs decl to the DO endpoint and, if DO accepted it as
+// an async task, polls that task until it finishes.
+func (pm *PostManager) postDODeclaration(decl string) (bool, string) {
+	url := fmt.Sprintf("https://%s%s", pm.BIGIPURL, doDeclareEndpoint)
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(decl))
+	if err != nil {
+		log.Errorf("[DO] Creating new HTTP request error: %v ", err)
+		return false, ""
+	}
+	req.SetBasicAuth(pm.BIGIPUsername, pm.BIGIPPassword)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := pm.httpClient.Do(req)
+	if err != nil {
+		log.Errorf("[DO] error while posting DO declaration: %v", err)
+		return false, ""
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return false, string(body)
+	}
+
+	var tasks []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &tasks); err != nil || len(tasks) == 0 {
+		// DO completed the declaration synchronously; nothing to poll.
+		return true, string(body)
+	}
+
+	return pm.pollDOTask(tasks[0].ID)
+}
+
+// pollDOTask polls /mgmt/shared/declarative-onboarding/task/<id> until DO
+// reports the onboarding task finished, failed, or doPollTimeout elapses.
+func (pm *PostManager) pollDOTask(taskID string) (bool, string) {
+	url := fmt.Sprintf("https://%s%s/%s", pm.BIGIPURL, doTaskEndpoint, taskID)
+	deadline := time.Now().Add(doPollTimeout)
+
+	for time.Now().Before(deadline) {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return false, ""
+		}
+		req.SetBasicAuth(pm.BIGIPUsername, pm.BIGIPPassword)
+
+		resp, err := pm.httpClient.Do(req)
+		if err != nil {
+			log.Errorf("[DO] error while polling DO task %v: %v", taskID, err)
+			return false, ""
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		var task struct {
+			Result struct {
+				Status string `json:"status"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(body, &task); err == nil {
+			switch task.Result.Status {
+			case "FINISHED":
+				return true, string(body)
+			case "ERROR", "FAILED":
+				return false, string(body)
+			}
+		}
+		time.Sleep(doPollInterval)
+	}
+	return false, "DO task polling timed out"
+}